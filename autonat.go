@@ -7,6 +7,7 @@ import (
 	"sync"
 	"time"
 
+	event "github.com/libp2p/go-libp2p-core/event"
 	host "github.com/libp2p/go-libp2p-host"
 	inet "github.com/libp2p/go-libp2p-net"
 	peer "github.com/libp2p/go-libp2p-peer"
@@ -27,13 +28,6 @@ const (
 	NATStatusPrivate
 )
 
-var (
-	AutoNATBootDelay       = 15 * time.Second
-	AutoNATRetryInterval   = 60 * time.Second
-	AutoNATRefreshInterval = 15 * time.Minute
-	AutoNATRequestTimeout  = 60 * time.Second
-)
-
 // AutoNAT is the interface for ambient NAT autodiscovery
 type AutoNAT interface {
 	// Status returns the current NAT status
@@ -41,39 +35,206 @@ type AutoNAT interface {
 	// PublicAddr returns the public dial address when NAT status is public and an
 	// error otherwise
 	PublicAddr() (ma.Multiaddr, error)
+	// EnableService turns on the AutoNAT dial-back service, so that this node
+	// also answers Dial requests for other peers. dialer is used to perform
+	// the dial-back attempts instead of the main host, so that results
+	// reflect true external dialability rather than reusing connections.
+	EnableService(dialer host.Host, opts ...ServiceOption) error
 }
 
 // AmbientAutoNAT is the implementation of ambient NAT autodiscovery
 type AmbientAutoNAT struct {
-	ctx  context.Context
-	host host.Host
+	ctx    context.Context
+	host   host.Host
+	config config
+
+	mx          sync.Mutex
+	peers       map[peer.ID]struct{}
+	status      NATStatus
+	addr        ma.Multiaddr
+	confidence  int
+	lastInbound time.Time
+
+	// recentProbes tracks the last time each peer was asked to dial us
+	// back, so the scheduler can favor servers that haven't been probed
+	// recently instead of hammering the same few.
+	recentProbes map[peer.ID]time.Time
+	// backoffStep counts consecutive high-confidence refresh cycles,
+	// driving the jittered exponential backoff applied to the refresh
+	// delay; it resets whenever confidence drops or new reachability
+	// evidence comes in.
+	backoffStep int
+
+	// inboundConn is fed public inbound connections observed by the
+	// notifiee; the background loop wakes up on it to re-evaluate NAT
+	// status without waiting for the next scheduled probe.
+	inboundConn chan inet.Conn
+
+	emitReachabilityChanged reachabilityEmitter
+
+	service *autoNATService
+}
+
+// recentInboundWindow bounds how long a public inbound connection is
+// considered strong evidence of public reachability.
+const recentInboundWindow = time.Minute
+
+// maxBackoffSteps caps how many times the refresh delay is doubled; with the
+// default 15m refreshInterval this tops out at a 2h delay before jitter.
+const maxBackoffSteps = 3
+
+// reachabilityEmitter publishes the current NATStatus onto the host's
+// eventbus whenever it changes. It emits one of the three
+// EvtLocalRoutability* events rather than a single combined
+// EvtLocalReachabilityChanged, since the pinned go-libp2p-core version this
+// package builds against doesn't define that event type.
+type reachabilityEmitter struct {
+	public  event.Emitter
+	private event.Emitter
+	unknown event.Emitter
+}
+
+func newReachabilityEmitter(bus event.Bus) (reachabilityEmitter, error) {
+	public, err := bus.Emitter(new(event.EvtLocalRoutabilityPublic))
+	if err != nil {
+		return reachabilityEmitter{}, err
+	}
+	private, err := bus.Emitter(new(event.EvtLocalRoutabilityPrivate))
+	if err != nil {
+		public.Close()
+		return reachabilityEmitter{}, err
+	}
+	unknown, err := bus.Emitter(new(event.EvtLocalRoutabilityUnknown))
+	if err != nil {
+		public.Close()
+		private.Close()
+		return reachabilityEmitter{}, err
+	}
+
+	return reachabilityEmitter{public: public, private: private, unknown: unknown}, nil
+}
+
+func (e reachabilityEmitter) Emit(status NATStatus) error {
+	switch status {
+	case NATStatusPublic:
+		return e.public.Emit(event.EvtLocalRoutabilityPublic{})
+	case NATStatusPrivate:
+		return e.private.Emit(event.EvtLocalRoutabilityPrivate{})
+	default:
+		return e.unknown.Emit(event.EvtLocalRoutabilityUnknown{})
+	}
+}
 
-	mx         sync.Mutex
-	peers      map[peer.ID]struct{}
-	status     NATStatus
-	addr       ma.Multiaddr
-	confidence int
+func (e reachabilityEmitter) Close() error {
+	e.public.Close()
+	e.private.Close()
+	e.unknown.Close()
+	return nil
 }
 
 // NewAutoNAT creates a new ambient NAT autodiscovery instance attached to a host
-func NewAutoNAT(ctx context.Context, h host.Host) AutoNAT {
+func NewAutoNAT(ctx context.Context, h host.Host, opts ...Option) (AutoNAT, error) {
+	c := config{}
+	defaults(&c)
+	for _, opt := range opts {
+		if err := opt(&c); err != nil {
+			return nil, err
+		}
+	}
+	if c.addressFunc == nil {
+		c.addressFunc = h.Addrs
+	}
+
+	emitter, err := newReachabilityEmitter(h.EventBus())
+	if err != nil {
+		return nil, err
+	}
+
+	idSub, err := h.EventBus().Subscribe(new(event.EvtPeerIdentificationCompleted))
+	if err != nil {
+		emitter.Close()
+		return nil, err
+	}
+
 	as := &AmbientAutoNAT{
-		ctx:    ctx,
-		host:   h,
-		peers:  make(map[peer.ID]struct{}),
-		status: NATStatusUnknown,
+		ctx:                     ctx,
+		host:                    h,
+		config:                  c,
+		peers:                   make(map[peer.ID]struct{}),
+		status:                  NATStatusUnknown,
+		recentProbes:            make(map[peer.ID]time.Time),
+		inboundConn:             make(chan inet.Conn, 1),
+		emitReachabilityChanged: emitter,
 	}
 
 	h.Network().Notify(as)
 	go as.background()
+	go as.ingestIdentifications(idSub)
+
+	return as, nil
+}
 
-	return as
+// ingestIdentifications adds peers that speak the AutoNAT protocol to the
+// ambient peer set as identify finishes for them. Protocol support isn't
+// known yet when Connected fires, since identify completes in a later,
+// separate stream round-trip, so we can't just check the peerstore there.
+func (as *AmbientAutoNAT) ingestIdentifications(sub event.Subscription) {
+	defer sub.Close()
+
+	for {
+		select {
+		case e, ok := <-sub.Out():
+			if !ok {
+				return
+			}
+			evt := e.(event.EvtPeerIdentificationCompleted)
+			protos, err := as.host.Peerstore().SupportsProtocols(evt.Peer, AutoNATProto)
+			if err != nil || len(protos) == 0 {
+				continue
+			}
+			as.mx.Lock()
+			as.peers[evt.Peer] = struct{}{}
+			as.mx.Unlock()
+		case <-as.ctx.Done():
+			return
+		}
+	}
 }
 
 func (as *AmbientAutoNAT) Status() NATStatus {
 	return as.status
 }
 
+// EnableService turns on the AutoNAT dial-back service for this node using
+// dialer as a separate host for dial-back attempts.
+func (as *AmbientAutoNAT) EnableService(dialer host.Host, opts ...ServiceOption) error {
+	as.mx.Lock()
+	defer as.mx.Unlock()
+
+	if as.service != nil {
+		return errors.New("AutoNAT service already enabled")
+	}
+
+	sc := serviceConfig{}
+	defaultServiceConfig(&sc)
+	for _, opt := range opts {
+		if err := opt(&sc); err != nil {
+			return err
+		}
+	}
+
+	svc := newAutoNATService(as.ctx, as.host, dialer, sc)
+	svc.start()
+	as.service = svc
+
+	go func() {
+		<-as.ctx.Done()
+		svc.close()
+	}()
+
+	return nil
+}
+
 func (as *AmbientAutoNAT) PublicAddr() (ma.Multiaddr, error) {
 	as.mx.Lock()
 	defer as.mx.Unlock()
@@ -86,10 +247,12 @@ func (as *AmbientAutoNAT) PublicAddr() (ma.Multiaddr, error) {
 }
 
 func (as *AmbientAutoNAT) background() {
+	defer as.emitReachabilityChanged.Close()
+
 	// wait a bit for the node to come online and establish some connections
 	// before starting autodetection
 	select {
-	case <-time.After(AutoNATBootDelay):
+	case <-time.After(as.config.bootDelay):
 	case <-as.ctx.Done():
 		return
 	}
@@ -97,12 +260,12 @@ func (as *AmbientAutoNAT) background() {
 	for {
 		as.autodetect()
 
-		delay := AutoNATRefreshInterval
-		if as.status == NATStatusUnknown {
-			delay = AutoNATRetryInterval
-		}
+		delay := as.nextProbeDelay()
 
 		select {
+		case <-as.inboundConn:
+			// a public inbound connection was observed; wake up and
+			// re-evaluate without waiting out the rest of the delay
 		case <-time.After(delay):
 		case <-as.ctx.Done():
 			return
@@ -110,7 +273,54 @@ func (as *AmbientAutoNAT) background() {
 	}
 }
 
+// nextProbeDelay picks how long to wait before the next probe cycle. While
+// unconfirmed it uses retryInterval; once confidence is high it backs off
+// the refreshInterval exponentially (with jitter) up to maxBackoffSteps, so
+// well-established nodes don't keep hammering public autonat servers.
+func (as *AmbientAutoNAT) nextProbeDelay() time.Duration {
+	as.mx.Lock()
+	defer as.mx.Unlock()
+
+	if as.status == NATStatusUnknown {
+		as.backoffStep = 0
+		return as.config.retryInterval
+	}
+
+	if as.confidence < 3 {
+		as.backoffStep = 0
+		return as.config.refreshInterval
+	}
+
+	delay := as.config.refreshInterval << uint(as.backoffStep)
+	if as.backoffStep < maxBackoffSteps {
+		as.backoffStep++
+	}
+
+	jitter := time.Duration(rand.Int63n(int64(delay)/4 + 1))
+	return delay + jitter
+}
+
 func (as *AmbientAutoNAT) autodetect() {
+	as.mx.Lock()
+	recentInbound := !as.lastInbound.IsZero() && time.Since(as.lastInbound) < recentInboundWindow
+	as.mx.Unlock()
+
+	if recentInbound {
+		log.Debugf("skipping probe; recent public inbound connection observed")
+		as.mx.Lock()
+		// Only treat this as new evidence (and reset the backoff) while
+		// confidence is still ramping up; once we're already confident,
+		// repeated inbound connections from the same well-connected node
+		// shouldn't keep undoing the backoff it earned.
+		if as.confidence < 3 {
+			as.confidence++
+			as.backoffStep = 0
+		}
+		as.mx.Unlock()
+		as.setStatus(NATStatusPublic)
+		return
+	}
+
 	peers := as.getPeers()
 
 	if len(peers) == 0 {
@@ -118,11 +328,15 @@ func (as *AmbientAutoNAT) autodetect() {
 		return
 	}
 
-	cli := NewAutoNATClient(as.host)
+	cli := NewAutoNATClient(as.host, as.config.addressFunc)
 	failures := 0
 
 	for _, p := range peers {
-		ctx, cancel := context.WithTimeout(as.ctx, AutoNATRequestTimeout)
+		as.mx.Lock()
+		as.recentProbes[p] = time.Now()
+		as.mx.Unlock()
+
+		ctx, cancel := context.WithTimeout(as.ctx, as.config.requestTimeout)
 		a, err := cli.DialBack(ctx, p)
 		cancel()
 
@@ -130,21 +344,32 @@ func (as *AmbientAutoNAT) autodetect() {
 		case err == nil:
 			log.Debugf("NAT status is public; address through %s: %s", p.Pretty(), a.String())
 			as.mx.Lock()
+			if as.addr == nil || !as.addr.Equal(a) {
+				as.backoffStep = 0
+			}
 			as.addr = a
-			as.status = NATStatusPublic
 			as.confidence = 0
 			as.mx.Unlock()
+			as.setStatus(NATStatusPublic)
 			return
 
+		case IsDialRefused(err):
+			// the server declined to dial us back at all; this tells us
+			// nothing about our own reachability, so don't let it count
+			// towards the failure tally. Move on to the next peer in this
+			// same probe cycle rather than waiting out a full refresh.
+			log.Debugf("dial refused by %s: %s", p.Pretty(), err.Error())
+			continue
+
 		case IsDialError(err):
 			log.Debugf("dial error through %s: %s", p.Pretty(), err.Error())
 			failures++
 			if failures >= 3 || as.confidence >= 3 { // 3 times is enemy action
 				log.Debugf("NAT status is private")
 				as.mx.Lock()
-				as.status = NATStatusPrivate
 				as.confidence = 3
 				as.mx.Unlock()
+				as.setStatus(NATStatusPrivate)
 				return
 			}
 
@@ -153,20 +378,54 @@ func (as *AmbientAutoNAT) autodetect() {
 		}
 	}
 
-	as.mx.Lock()
 	if failures > 0 {
-		as.status = NATStatusPrivate
+		as.mx.Lock()
 		as.confidence++
+		as.mx.Unlock()
 		log.Debugf("NAT status is private")
+		as.setStatus(NATStatusPrivate)
 	} else {
-		as.status = NATStatusUnknown
+		as.mx.Lock()
 		as.confidence = 0
+		as.mx.Unlock()
 		log.Debugf("NAT status is unknown")
+		as.setStatus(NATStatusUnknown)
 	}
+}
+
+// setStatus updates the current NAT status and, if it actually changed,
+// publishes the new reachability onto the host's eventbus.
+func (as *AmbientAutoNAT) setStatus(s NATStatus) {
+	as.mx.Lock()
+	changed := as.status != s
+	as.status = s
 	as.mx.Unlock()
+
+	if !changed {
+		return
+	}
+
+	if err := as.emitReachabilityChanged.Emit(s); err != nil {
+		log.Warningf("failed to emit reachability changed: %s", err)
+	}
+
+	if as.config.metricsTracer != nil {
+		as.config.metricsTracer.ReachabilityStatus(s)
+	}
 }
 
 func (as *AmbientAutoNAT) getPeers() []peer.ID {
+	var peers []peer.ID
+	if as.config.peerSource != nil {
+		peers = as.getPeersFromSource()
+	} else {
+		peers = as.getKnownPeers()
+	}
+
+	return as.preferUnthrottled(peers)
+}
+
+func (as *AmbientAutoNAT) getKnownPeers() []peer.ID {
 	as.mx.Lock()
 	defer as.mx.Unlock()
 
@@ -193,6 +452,58 @@ func (as *AmbientAutoNAT) getPeers() []peer.ID {
 	return peers
 }
 
+// preferUnthrottled drops peers that were probed within probeThrottle of
+// now, so load spreads across known autonat servers instead of piling onto
+// whichever ones happen to be seen most often. If every candidate was
+// probed recently, it falls back to probing anyway rather than stalling.
+// While it's here, it also prunes recentProbes of entries that have aged
+// out of the throttle window, so the map doesn't grow without bound over
+// the life of the process.
+func (as *AmbientAutoNAT) preferUnthrottled(peers []peer.ID) []peer.ID {
+	as.mx.Lock()
+	defer as.mx.Unlock()
+
+	fresh := make([]peer.ID, 0, len(peers))
+	for _, p := range peers {
+		if t, ok := as.recentProbes[p]; !ok || time.Since(t) >= as.config.probeThrottle {
+			fresh = append(fresh, p)
+		}
+	}
+
+	for p, t := range as.recentProbes {
+		if time.Since(t) >= as.config.probeThrottle {
+			delete(as.recentProbes, p)
+		}
+	}
+
+	if len(fresh) == 0 {
+		return peers
+	}
+	return fresh
+}
+
+// getPeersFromSource drains candidate autonat servers off the configured
+// peer source, adding their addresses to the peerstore so they can be dialed.
+func (as *AmbientAutoNAT) getPeersFromSource() []peer.ID {
+	ctx, cancel := context.WithTimeout(as.ctx, as.config.requestTimeout)
+	defer cancel()
+
+	var peers []peer.ID
+	ch := as.config.peerSource(ctx)
+	for {
+		select {
+		case pi, ok := <-ch:
+			if !ok {
+				return peers
+			}
+			as.host.Peerstore().AddAddrs(pi.ID, pi.Addrs, time.Hour)
+			peers = append(peers, pi.ID)
+		case <-ctx.Done():
+			return peers
+		}
+	}
+}
+
 func shufflePeers(peers []peer.ID) {
 	for i := range peers {
 		j := rand.Intn(i + 1)
@@ -0,0 +1,134 @@
+package autonat
+
+import (
+	"testing"
+	"time"
+
+	peer "github.com/libp2p/go-libp2p-peer"
+)
+
+func newTestAmbientAutoNAT(c config) *AmbientAutoNAT {
+	return &AmbientAutoNAT{
+		config:       c,
+		recentProbes: make(map[peer.ID]time.Time),
+	}
+}
+
+func TestNextProbeDelayUnknownUsesRetryInterval(t *testing.T) {
+	c := config{}
+	defaults(&c)
+	as := newTestAmbientAutoNAT(c)
+	as.status = NATStatusUnknown
+
+	if d := as.nextProbeDelay(); d != c.retryInterval {
+		t.Fatalf("expected retryInterval %s while status is unknown, got %s", c.retryInterval, d)
+	}
+	if as.backoffStep != 0 {
+		t.Fatalf("backoffStep should stay at 0 while status is unknown, got %d", as.backoffStep)
+	}
+}
+
+func TestNextProbeDelayBacksOffWithConfidence(t *testing.T) {
+	c := config{}
+	defaults(&c)
+	as := newTestAmbientAutoNAT(c)
+	as.status = NATStatusPublic
+	as.confidence = 3
+
+	for i := 0; i <= maxBackoffSteps+2; i++ {
+		wantStep := i
+		if wantStep > maxBackoffSteps {
+			wantStep = maxBackoffSteps
+		}
+
+		delay := as.nextProbeDelay()
+
+		// Each step's base delay (before jitter) should double the last,
+		// capping once backoffStep hits maxBackoffSteps; jitter alone can
+		// make a later draw come out smaller than an earlier one, so we
+		// only assert the per-step range, not a running minimum.
+		minDelay := c.refreshInterval << uint(wantStep)
+		maxDelay := minDelay + minDelay/4
+		if delay < minDelay || delay > maxDelay {
+			t.Fatalf("step %d: delay %s out of expected [%s, %s] range", i, delay, minDelay, maxDelay)
+		}
+	}
+
+	if as.backoffStep != maxBackoffSteps {
+		t.Fatalf("expected backoffStep to cap at %d, got %d", maxBackoffSteps, as.backoffStep)
+	}
+}
+
+func TestNextProbeDelayLowConfidenceResetsBackoff(t *testing.T) {
+	c := config{}
+	defaults(&c)
+	as := newTestAmbientAutoNAT(c)
+	as.status = NATStatusPublic
+	as.confidence = 3
+
+	as.nextProbeDelay()
+	as.nextProbeDelay()
+	if as.backoffStep == 0 {
+		t.Fatal("backoffStep should have advanced past 0 with high confidence")
+	}
+
+	as.confidence = 0
+	if d := as.nextProbeDelay(); d != c.refreshInterval {
+		t.Fatalf("expected bare refreshInterval once confidence drops, got %s", d)
+	}
+	if as.backoffStep != 0 {
+		t.Fatalf("backoffStep should reset to 0 once confidence drops, got %d", as.backoffStep)
+	}
+}
+
+func TestPreferUnthrottledFiltersRecentlyProbed(t *testing.T) {
+	c := config{}
+	defaults(&c)
+	c.probeThrottle = time.Hour
+	as := newTestAmbientAutoNAT(c)
+
+	p1, p2 := peer.ID("peer1"), peer.ID("peer2")
+	as.recentProbes[p1] = time.Now()
+
+	fresh := as.preferUnthrottled([]peer.ID{p1, p2})
+	if len(fresh) != 1 || fresh[0] != p2 {
+		t.Fatalf("expected only p2 to pass the throttle, got %v", fresh)
+	}
+}
+
+func TestPreferUnthrottledFallsBackWhenAllThrottled(t *testing.T) {
+	c := config{}
+	defaults(&c)
+	c.probeThrottle = time.Hour
+	as := newTestAmbientAutoNAT(c)
+
+	p1, p2 := peer.ID("peer1"), peer.ID("peer2")
+	as.recentProbes[p1] = time.Now()
+	as.recentProbes[p2] = time.Now()
+
+	peers := []peer.ID{p1, p2}
+	fresh := as.preferUnthrottled(peers)
+	if len(fresh) != len(peers) {
+		t.Fatalf("expected to fall back to probing anyway when every candidate is throttled, got %v", fresh)
+	}
+}
+
+func TestPreferUnthrottledPrunesStaleEntries(t *testing.T) {
+	c := config{}
+	defaults(&c)
+	c.probeThrottle = 10 * time.Millisecond
+	as := newTestAmbientAutoNAT(c)
+
+	p1, p2 := peer.ID("peer1"), peer.ID("peer2")
+	as.recentProbes[p1] = time.Now().Add(-time.Hour)
+	as.recentProbes[p2] = time.Now()
+
+	as.preferUnthrottled([]peer.ID{p1, p2})
+
+	if _, ok := as.recentProbes[p1]; ok {
+		t.Fatal("expected the stale entry for p1 to be pruned")
+	}
+	if _, ok := as.recentProbes[p2]; !ok {
+		t.Fatal("did not expect the fresh entry for p2 to be pruned")
+	}
+}
@@ -0,0 +1,95 @@
+package autonat
+
+import (
+	"context"
+	"fmt"
+
+	pb "github.com/libp2p/go-libp2p-autonat/pb"
+
+	ggio "github.com/gogo/protobuf/io"
+
+	peer "github.com/libp2p/go-libp2p-core/peer"
+	host "github.com/libp2p/go-libp2p-host"
+	inet "github.com/libp2p/go-libp2p-net"
+	ma "github.com/multiformats/go-multiaddr"
+)
+
+// AutoNATProto identifies the autonat service protocol
+const AutoNATProto = "/libp2p/autonat/1.0.0"
+
+// NewAutoNATClient creates a fresh instance of an AutoNATClient. If
+// addrFunc is nil, h.Addrs is used to determine which addresses to offer up
+// for dial-back.
+func NewAutoNATClient(h host.Host, addrFunc func() []ma.Multiaddr) Client {
+	if addrFunc == nil {
+		addrFunc = h.Addrs
+	}
+	return &client{h: h, addrFunc: addrFunc}
+}
+
+// Client is a stateless client for the AutoNAT dial-back protocol; it sends
+// a Dial request to a remote AutoNAT peer and reports the address that peer
+// was able to dial back on.
+type Client interface {
+	DialBack(ctx context.Context, p peer.ID) (ma.Multiaddr, error)
+}
+
+type client struct {
+	h        host.Host
+	addrFunc func() []ma.Multiaddr
+}
+
+// DialBack asks peer p to dial us back on one of our listen addresses and
+// returns the address it succeeded on.
+func (c *client) DialBack(ctx context.Context, p peer.ID) (ma.Multiaddr, error) {
+	s, err := c.h.NewStream(ctx, p, AutoNATProto)
+	if err != nil {
+		return nil, err
+	}
+	defer s.Reset()
+
+	r := ggio.NewDelimitedReader(s, inet.MessageSizeMax)
+	w := ggio.NewDelimitedWriter(s)
+
+	req := newDialMessage(peer.AddrInfo{ID: c.h.ID(), Addrs: c.addrFunc()})
+	if err := w.WriteMsg(req); err != nil {
+		return nil, err
+	}
+
+	var res pb.Message
+	if err := r.ReadMsg(&res); err != nil {
+		return nil, err
+	}
+	if res.GetType() != pb.Message_DIAL_RESPONSE {
+		return nil, fmt.Errorf("unexpected response: %s", res.GetType().String())
+	}
+
+	status := res.GetDialResponse().GetStatus()
+	switch status {
+	case pb.Message_OK:
+		return ma.NewMultiaddrBytes(res.GetDialResponse().GetAddr())
+
+	case pb.Message_E_DIAL_REFUSED:
+		return nil, newDialRefusedError(res.GetDialResponse().GetStatusText())
+
+	case pb.Message_E_DIAL_ERROR:
+		return nil, newDialError(status, res.GetDialResponse().GetStatusText())
+
+	default:
+		return nil, fmt.Errorf("autonat error: %s (%s)", status.String(), res.GetDialResponse().GetStatusText())
+	}
+}
+
+func newDialMessage(pi peer.AddrInfo) *pb.Message {
+	msg := new(pb.Message)
+	msg.Type = pb.Message_DIAL.Enum()
+	msg.Dial = new(pb.Message_Dial)
+	msg.Dial.Peer = new(pb.Message_PeerInfo)
+	msg.Dial.Peer.Id = []byte(pi.ID)
+	msg.Dial.Peer.Addrs = make([][]byte, len(pi.Addrs))
+	for i, addr := range pi.Addrs {
+		msg.Dial.Peer.Addrs[i] = addr.Bytes()
+	}
+
+	return msg
+}
@@ -0,0 +1,47 @@
+package autonat
+
+import (
+	ma "github.com/multiformats/go-multiaddr"
+	manet "github.com/multiformats/go-multiaddr-net"
+)
+
+// dialPolicy decides which addresses the AutoNAT service is willing to dial
+// back on.
+type dialPolicy struct {
+	skipPrivate       bool
+	skipRelayed       bool
+	allowedTransports []int // multiaddr protocol codes; empty means all
+}
+
+// skipDial returns true if addr should not be used for a dial-back attempt.
+func (d dialPolicy) skipDial(addr ma.Multiaddr) bool {
+	if d.skipRelayed && isRelayedAddr(addr) {
+		return true
+	}
+
+	if d.skipPrivate && !manet.IsPublicAddr(addr) {
+		return true
+	}
+
+	if len(d.allowedTransports) > 0 && !d.transportAllowed(addr) {
+		return true
+	}
+
+	return false
+}
+
+func (d dialPolicy) transportAllowed(addr ma.Multiaddr) bool {
+	for _, p := range addr.Protocols() {
+		for _, allowed := range d.allowedTransports {
+			if p.Code == allowed {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func isRelayedAddr(addr ma.Multiaddr) bool {
+	_, err := addr.ValueForProtocol(ma.P_CIRCUIT)
+	return err == nil
+}
@@ -0,0 +1,58 @@
+package autonat
+
+import (
+	"fmt"
+
+	pb "github.com/libp2p/go-libp2p-autonat/pb"
+)
+
+// DialError is the error returned when a remote AutoNAT peer attempted, but
+// failed, to dial us back.
+type DialError struct {
+	Cause pb.Message_ResponseStatus
+	Text  string
+}
+
+func (e DialError) Error() string {
+	if e.Text != "" {
+		return fmt.Sprintf("dial error: %s (%s)", e.Cause.String(), e.Text)
+	}
+	return fmt.Sprintf("dial error: %s", e.Cause.String())
+}
+
+func newDialError(status pb.Message_ResponseStatus, text string) error {
+	return DialError{Cause: status, Text: text}
+}
+
+// IsDialError returns true if the passed in error was due to a dial back
+// failure reported by a remote AutoNAT peer.
+func IsDialError(e error) bool {
+	_, ok := e.(DialError)
+	return ok
+}
+
+// DialRefusedError is returned when a remote AutoNAT peer declines to
+// attempt a dial-back at all, e.g. because we offered no dialable
+// addresses or it refused for policy reasons. Unlike DialError, this is not
+// evidence of our own NAT status and should not count towards it.
+type DialRefusedError struct {
+	Text string
+}
+
+func (e DialRefusedError) Error() string {
+	if e.Text != "" {
+		return fmt.Sprintf("dial refused: %s", e.Text)
+	}
+	return "dial refused"
+}
+
+func newDialRefusedError(text string) error {
+	return DialRefusedError{Text: text}
+}
+
+// IsDialRefused returns true if the passed in error was due to a remote
+// AutoNAT peer declining to attempt a dial-back.
+func IsDialRefused(e error) bool {
+	_, ok := e.(DialRefusedError)
+	return ok
+}
@@ -0,0 +1,7 @@
+package autonat
+
+import (
+	logging "github.com/ipfs/go-log"
+)
+
+var log = logging.Logger("autonat")
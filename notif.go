@@ -0,0 +1,44 @@
+package autonat
+
+import (
+	"time"
+
+	inet "github.com/libp2p/go-libp2p-net"
+	ma "github.com/multiformats/go-multiaddr"
+	manet "github.com/multiformats/go-multiaddr-net"
+)
+
+// Notifiee is the interface implemented by AmbientAutoNAT to keep track of
+// the network events it cares about.
+var _ inet.Notifiee = (*AmbientAutoNAT)(nil)
+
+func (as *AmbientAutoNAT) Listen(net inet.Network, a ma.Multiaddr)      {}
+func (as *AmbientAutoNAT) ListenClose(net inet.Network, a ma.Multiaddr) {}
+func (as *AmbientAutoNAT) OpenedStream(net inet.Network, s inet.Stream) {}
+func (as *AmbientAutoNAT) ClosedStream(net inet.Network, s inet.Stream) {}
+
+// Connected records public inbound connections as strong evidence of
+// reachability; see recentInboundWindow. Membership in the ambient peer set
+// is handled separately, by ingestIdentifications: protocol support isn't
+// known yet at connect time, since identify hasn't had a chance to run.
+func (as *AmbientAutoNAT) Connected(net inet.Network, c inet.Conn) {
+	if c.Stat().Direction != inet.DirInbound {
+		return
+	}
+
+	ra := c.RemoteMultiaddr()
+	if !manet.IsPublicAddr(ra) {
+		return
+	}
+
+	as.mx.Lock()
+	as.lastInbound = time.Now()
+	as.mx.Unlock()
+
+	select {
+	case as.inboundConn <- c:
+	default:
+	}
+}
+
+func (as *AmbientAutoNAT) Disconnected(net inet.Network, c inet.Conn) {}
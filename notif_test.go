@@ -0,0 +1,163 @@
+package autonat
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	ic "github.com/libp2p/go-libp2p-core/crypto"
+	event "github.com/libp2p/go-libp2p-core/event"
+	peerstore "github.com/libp2p/go-libp2p-core/peerstore"
+	host "github.com/libp2p/go-libp2p-host"
+	inet "github.com/libp2p/go-libp2p-net"
+	peer "github.com/libp2p/go-libp2p-peer"
+	ma "github.com/multiformats/go-multiaddr"
+)
+
+// fakePeerstore only implements SupportsProtocols; every other method panics
+// via the embedded nil interface, which is fine since the tests below never
+// exercise them.
+type fakePeerstore struct {
+	peerstore.Peerstore
+	protocols map[peer.ID][]string
+}
+
+func (ps *fakePeerstore) SupportsProtocols(p peer.ID, protos ...string) ([]string, error) {
+	return ps.protocols[p], nil
+}
+
+// fakeHost only implements Peerstore; see fakePeerstore.
+type fakeHost struct {
+	host.Host
+	ps *fakePeerstore
+}
+
+func (h *fakeHost) Peerstore() peerstore.Peerstore { return h.ps }
+
+// fakeConn only implements the bits of inet.Conn that Connected touches.
+type fakeConn struct {
+	inet.Conn
+	remote    peer.ID
+	remoteMa  ma.Multiaddr
+	direction inet.Direction
+}
+
+func (c *fakeConn) RemotePeer() peer.ID           { return c.remote }
+func (c *fakeConn) RemoteMultiaddr() ma.Multiaddr { return c.remoteMa }
+func (c *fakeConn) LocalPrivateKey() ic.PrivKey   { return nil }
+func (c *fakeConn) Stat() inet.Stat               { return inet.Stat{Direction: c.direction} }
+
+// fakeSub is a directly-controlled event.Subscription, so tests don't need a
+// real eventbus.
+type fakeSub struct {
+	out chan interface{}
+}
+
+func (s *fakeSub) Out() <-chan interface{} { return s.out }
+func (s *fakeSub) Close() error            { return nil }
+
+func TestConnectedDoesNotRegisterPeerBeforeIdentify(t *testing.T) {
+	p := peer.ID("peer1")
+	ps := &fakePeerstore{protocols: map[peer.ID][]string{p: {AutoNATProto}}}
+	as := &AmbientAutoNAT{
+		host:  &fakeHost{ps: ps},
+		peers: make(map[peer.ID]struct{}),
+	}
+
+	publicAddr, err := ma.NewMultiaddr("/ip4/1.2.3.4/tcp/1234")
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := &fakeConn{remote: p, remoteMa: publicAddr, direction: inet.DirInbound}
+
+	as.Connected(nil, c)
+
+	// Even though the peerstore already has protocol info cached (e.g. from
+	// a previous connection), Connected itself must not decide membership;
+	// that's ingestIdentifications' job, off the identify-completed event.
+	as.mx.Lock()
+	_, isPeer := as.peers[p]
+	as.mx.Unlock()
+	if isPeer {
+		t.Fatal("Connected should not add peers to the ambient set directly")
+	}
+
+	as.mx.Lock()
+	lastInbound := as.lastInbound
+	as.mx.Unlock()
+	if lastInbound.IsZero() {
+		t.Fatal("Connected should still record the public inbound connection")
+	}
+}
+
+func TestIngestIdentificationsAddsSupportingPeer(t *testing.T) {
+	p := peer.ID("peer1")
+	ps := &fakePeerstore{protocols: map[peer.ID][]string{p: {AutoNATProto}}}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	as := &AmbientAutoNAT{
+		ctx:   ctx,
+		host:  &fakeHost{ps: ps},
+		peers: make(map[peer.ID]struct{}),
+	}
+
+	sub := &fakeSub{out: make(chan interface{}, 1)}
+	done := make(chan struct{})
+	go func() {
+		as.ingestIdentifications(sub)
+		close(done)
+	}()
+
+	sub.out <- event.EvtPeerIdentificationCompleted{Peer: p}
+	close(sub.out)
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("ingestIdentifications did not return after its channel closed")
+	}
+
+	as.mx.Lock()
+	_, isPeer := as.peers[p]
+	as.mx.Unlock()
+	if !isPeer {
+		t.Fatal("expected the identified peer to be added to the ambient peer set")
+	}
+}
+
+func TestIngestIdentificationsSkipsNonSupportingPeer(t *testing.T) {
+	p := peer.ID("peer1")
+	ps := &fakePeerstore{protocols: map[peer.ID][]string{}}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	as := &AmbientAutoNAT{
+		ctx:   ctx,
+		host:  &fakeHost{ps: ps},
+		peers: make(map[peer.ID]struct{}),
+	}
+
+	sub := &fakeSub{out: make(chan interface{}, 1)}
+	done := make(chan struct{})
+	go func() {
+		as.ingestIdentifications(sub)
+		close(done)
+	}()
+
+	sub.out <- event.EvtPeerIdentificationCompleted{Peer: p}
+	close(sub.out)
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("ingestIdentifications did not return after its channel closed")
+	}
+
+	as.mx.Lock()
+	_, isPeer := as.peers[p]
+	as.mx.Unlock()
+	if isPeer {
+		t.Fatal("expected a peer not speaking AutoNATProto to be left out of the ambient peer set")
+	}
+}
@@ -0,0 +1,110 @@
+package autonat
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	peer "github.com/libp2p/go-libp2p-core/peer"
+	ma "github.com/multiformats/go-multiaddr"
+)
+
+// Option is an AutoNAT option for use with NewAutoNAT.
+type Option func(*config) error
+
+type config struct {
+	bootDelay       time.Duration
+	retryInterval   time.Duration
+	refreshInterval time.Duration
+	requestTimeout  time.Duration
+
+	// probeThrottle is the minimum time between two probes of the same
+	// peer; servers that were probed more recently than this are passed
+	// over in favor of ones that weren't.
+	probeThrottle time.Duration
+
+	addressFunc   func() []ma.Multiaddr
+	peerSource    func(ctx context.Context) <-chan peer.AddrInfo
+	metricsTracer MetricsTracer
+}
+
+func defaults(c *config) {
+	c.bootDelay = 15 * time.Second
+	c.retryInterval = 60 * time.Second
+	c.refreshInterval = 15 * time.Minute
+	c.requestTimeout = 60 * time.Second
+	c.probeThrottle = 30 * time.Second
+}
+
+// WithSchedule configures how often AutoNAT should ask peers to dial it back.
+// bootDelay is the time to wait before the first probe, retryInterval is how
+// often to probe while the NAT status is unknown, and refreshInterval is how
+// often to re-probe once a status has been established.
+func WithSchedule(bootDelay, retryInterval, refreshInterval time.Duration) Option {
+	return func(c *config) error {
+		c.bootDelay = bootDelay
+		c.retryInterval = retryInterval
+		c.refreshInterval = refreshInterval
+		return nil
+	}
+}
+
+// WithRequestTimeout sets the timeout for a single dial-back request to an
+// AutoNAT peer.
+func WithRequestTimeout(timeout time.Duration) Option {
+	return func(c *config) error {
+		if timeout == 0 {
+			return errors.New("request timeout must be non-zero")
+		}
+		c.requestTimeout = timeout
+		return nil
+	}
+}
+
+// WithAddressFunc overrides the default addresses used for dial-back probes
+// (host.Addrs) with a custom function, e.g. to advertise only the subset of
+// addresses that have passed through autorelay filtering.
+func WithAddressFunc(f func() []ma.Multiaddr) Option {
+	return func(c *config) error {
+		c.addressFunc = f
+		return nil
+	}
+}
+
+// WithPeerSource configures a function returning a channel of candidate
+// AutoNAT server peers. When set, it replaces the default discovery, which
+// probes known peers that speak the AutoNAT protocol (see
+// ingestIdentifications), preferring ones we're currently connected to,
+// letting embedders plug in their own peer discovery (e.g. a DHT-backed
+// peer sampler).
+func WithPeerSource(f func(ctx context.Context) <-chan peer.AddrInfo) Option {
+	return func(c *config) error {
+		c.peerSource = f
+		return nil
+	}
+}
+
+// WithPeerThrottle sets the minimum time that must pass before the same
+// peer is probed again, so that load doesn't concentrate on whichever
+// autonat servers happen to be seen most often.
+func WithPeerThrottle(window time.Duration) Option {
+	return func(c *config) error {
+		c.probeThrottle = window
+		return nil
+	}
+}
+
+// WithMetricsTracer configures a MetricsTracer to receive notifications of
+// reachability changes.
+func WithMetricsTracer(mt MetricsTracer) Option {
+	return func(c *config) error {
+		c.metricsTracer = mt
+		return nil
+	}
+}
+
+// MetricsTracer is implemented by consumers that want to observe AutoNAT
+// reachability changes, e.g. to export them as metrics.
+type MetricsTracer interface {
+	ReachabilityStatus(status NATStatus)
+}
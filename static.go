@@ -0,0 +1,90 @@
+package autonat
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	host "github.com/libp2p/go-libp2p-host"
+	ma "github.com/multiformats/go-multiaddr"
+)
+
+// StaticAutoNAT is an AutoNAT implementation for nodes that already know
+// their own reachability, such as a server deployment with a known public
+// IP, or a mobile node that is definitely behind a NAT. It skips all
+// probing and reports the configured status immediately, rather than
+// waiting out the boot delay (see WithSchedule) for an answer that would
+// come back the same either way.
+type StaticAutoNAT struct {
+	host         host.Host
+	reachability NATStatus
+	addr         ma.Multiaddr
+
+	mx      sync.Mutex
+	service *autoNATService
+}
+
+// NewStaticAutoNAT creates an AutoNAT instance that reports a fixed
+// reachability without ever dialing back through a peer. It emits a
+// one-shot reachability event on the host's eventbus so that subsystems
+// listening for reachability changes get an answer right away.
+func NewStaticAutoNAT(h host.Host, reachability NATStatus, addr ma.Multiaddr) (AutoNAT, error) {
+	s := &StaticAutoNAT{
+		host:         h,
+		reachability: reachability,
+		addr:         addr,
+	}
+
+	emitter, err := newReachabilityEmitter(h.EventBus())
+	if err != nil {
+		return nil, err
+	}
+	defer emitter.Close()
+
+	if err := emitter.Emit(reachability); err != nil {
+		log.Warningf("failed to emit reachability changed: %s", err)
+	}
+
+	return s, nil
+}
+
+func (s *StaticAutoNAT) Status() NATStatus {
+	return s.reachability
+}
+
+func (s *StaticAutoNAT) PublicAddr() (ma.Multiaddr, error) {
+	if s.reachability != NATStatusPublic {
+		return nil, errors.New("NAT status is not public")
+	}
+	if s.addr == nil {
+		return nil, errors.New("no public address configured")
+	}
+	return s.addr, nil
+}
+
+// EnableService turns on the AutoNAT dial-back service for this node using
+// dialer as a separate host for dial-back attempts. Knowing our own
+// reachability doesn't stop us from answering other peers' dial requests,
+// so this is wired the same way as AmbientAutoNAT.EnableService.
+func (s *StaticAutoNAT) EnableService(dialer host.Host, opts ...ServiceOption) error {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	if s.service != nil {
+		return errors.New("AutoNAT service already enabled")
+	}
+
+	sc := serviceConfig{}
+	defaultServiceConfig(&sc)
+	for _, opt := range opts {
+		if err := opt(&sc); err != nil {
+			return err
+		}
+	}
+
+	svc := newAutoNATService(context.Background(), s.host, dialer, sc)
+	svc.start()
+	s.service = svc
+
+	return nil
+}
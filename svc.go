@@ -0,0 +1,257 @@
+package autonat
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	pb "github.com/libp2p/go-libp2p-autonat/pb"
+
+	ggio "github.com/gogo/protobuf/io"
+
+	peer "github.com/libp2p/go-libp2p-core/peer"
+	peerstore "github.com/libp2p/go-libp2p-core/peerstore"
+	host "github.com/libp2p/go-libp2p-host"
+	inet "github.com/libp2p/go-libp2p-net"
+	ma "github.com/multiformats/go-multiaddr"
+)
+
+// ServiceOption is an option for EnableService.
+type ServiceOption func(*serviceConfig) error
+
+type serviceConfig struct {
+	dialPolicy dialPolicy
+
+	dialTimeout           time.Duration
+	throttleResetInterval time.Duration
+	throttleGlobalMax     int
+	throttlePeerMax       int
+	maxConcurrentDials    int
+}
+
+func defaultServiceConfig(c *serviceConfig) {
+	c.dialTimeout = 15 * time.Second
+	c.throttleResetInterval = time.Minute
+	c.throttleGlobalMax = 30
+	c.throttlePeerMax = 3
+	c.maxConcurrentDials = 5
+}
+
+// WithThrottling limits how many dial-back requests the service will serve
+// within interval, both globally (max) and per requesting peer (peerMax).
+func WithThrottling(max, peerMax int, interval time.Duration) ServiceOption {
+	return func(c *serviceConfig) error {
+		c.throttleGlobalMax = max
+		c.throttlePeerMax = peerMax
+		c.throttleResetInterval = interval
+		return nil
+	}
+}
+
+// WithMaxConcurrentDials bounds how many dial-back attempts may be in flight
+// at any given time.
+func WithMaxConcurrentDials(n int) ServiceOption {
+	return func(c *serviceConfig) error {
+		c.maxConcurrentDials = n
+		return nil
+	}
+}
+
+// WithDialOnlyTransports restricts dial-back attempts to addresses using one
+// of the given multiaddr protocol codes (e.g. ma.P_TCP, ma.P_QUIC).
+func WithDialOnlyTransports(protocols ...int) ServiceOption {
+	return func(c *serviceConfig) error {
+		c.dialPolicy.allowedTransports = protocols
+		return nil
+	}
+}
+
+// WithoutPrivateAddrs refuses to dial back on addresses that appear to be on
+// a private network.
+func WithoutPrivateAddrs() ServiceOption {
+	return func(c *serviceConfig) error {
+		c.dialPolicy.skipPrivate = true
+		return nil
+	}
+}
+
+// WithoutRelayedAddrs refuses to dial back through relayed (/p2p-circuit)
+// addresses.
+func WithoutRelayedAddrs() ServiceOption {
+	return func(c *serviceConfig) error {
+		c.dialPolicy.skipRelayed = true
+		return nil
+	}
+}
+
+// autoNATService provides the server side of the AutoNAT dial-back
+// protocol: it listens for Dial requests and attempts to dial the
+// requesting peer back on a separate dialer host, so that the result
+// reflects true external dialability rather than reusing an existing
+// connection.
+type autoNATService struct {
+	ctx    context.Context
+	host   host.Host
+	dialer host.Host
+	config serviceConfig
+
+	dialSem chan struct{}
+
+	mx         sync.Mutex
+	reqs       map[peer.ID]int
+	globalReqs int
+	lastReset  time.Time
+}
+
+func newAutoNATService(ctx context.Context, h, dialer host.Host, c serviceConfig) *autoNATService {
+	return &autoNATService{
+		ctx:       ctx,
+		host:      h,
+		dialer:    dialer,
+		config:    c,
+		dialSem:   make(chan struct{}, c.maxConcurrentDials),
+		reqs:      make(map[peer.ID]int),
+		lastReset: time.Now(),
+	}
+}
+
+func (as *autoNATService) start() {
+	as.host.SetStreamHandler(AutoNATProto, as.handleStream)
+}
+
+func (as *autoNATService) close() {
+	as.host.RemoveStreamHandler(AutoNATProto)
+}
+
+func (as *autoNATService) handleStream(s inet.Stream) {
+	defer s.Close()
+
+	pid := s.Conn().RemotePeer()
+	log.Debugf("New stream from %s", pid.Pretty())
+
+	r := ggio.NewDelimitedReader(s, inet.MessageSizeMax)
+	w := ggio.NewDelimitedWriter(s)
+
+	var req pb.Message
+	if err := r.ReadMsg(&req); err != nil {
+		log.Debugf("Error reading message from %s: %s", pid.Pretty(), err.Error())
+		s.Reset()
+		return
+	}
+
+	if req.GetType() != pb.Message_DIAL {
+		log.Debugf("Unexpected message from %s: %s", pid.Pretty(), req.GetType().String())
+		s.Reset()
+		return
+	}
+
+	res := as.handleDial(pid, s.Conn().RemoteMultiaddr(), req.GetDial().GetPeer())
+	if err := w.WriteMsg(res); err != nil {
+		log.Debugf("Error writing response to %s: %s", pid.Pretty(), err.Error())
+		s.Reset()
+	}
+}
+
+func (as *autoNATService) handleDial(p peer.ID, obsAddr ma.Multiaddr, mpi *pb.Message_PeerInfo) *pb.Message {
+	if mpi == nil {
+		return newDialResponseError(pb.Message_E_BAD_REQUEST, "missing peer info")
+	}
+
+	if rid, err := peer.IDFromBytes(mpi.GetId()); err == nil && rid != "" && rid != p {
+		return newDialResponseError(pb.Message_E_BAD_REQUEST, "peer id mismatch")
+	}
+
+	addrs := make([]ma.Multiaddr, 0, len(mpi.GetAddrs()))
+	for _, ab := range mpi.GetAddrs() {
+		a, err := ma.NewMultiaddrBytes(ab)
+		if err != nil {
+			continue
+		}
+		if !as.config.dialPolicy.skipDial(a) {
+			addrs = append(addrs, a)
+		}
+	}
+	if obsAddr != nil && !as.config.dialPolicy.skipDial(obsAddr) {
+		addrs = append([]ma.Multiaddr{obsAddr}, addrs...)
+	}
+
+	if len(addrs) == 0 {
+		return newDialResponseError(pb.Message_E_DIAL_REFUSED, "no dialable addresses")
+	}
+
+	if !as.throttleAllow(p) {
+		return newDialResponseError(pb.Message_E_DIAL_REFUSED, "too many dials")
+	}
+
+	select {
+	case as.dialSem <- struct{}{}:
+	default:
+		return newDialResponseError(pb.Message_E_DIAL_REFUSED, "too many concurrent dials")
+	}
+	defer func() { <-as.dialSem }()
+
+	return as.doDial(p, addrs)
+}
+
+func (as *autoNATService) doDial(p peer.ID, addrs []ma.Multiaddr) *pb.Message {
+	ctx, cancel := context.WithTimeout(as.ctx, as.config.dialTimeout)
+	defer cancel()
+
+	as.dialer.Peerstore().AddAddrs(p, addrs, peerstore.TempAddrTTL)
+	defer as.dialer.Network().ClosePeer(p)
+
+	conn, err := as.dialer.Network().DialPeer(ctx, p)
+	if err != nil {
+		log.Debugf("error dialing back %s: %s", p.Pretty(), err.Error())
+		return newDialResponseError(pb.Message_E_DIAL_ERROR, err.Error())
+	}
+
+	return newDialResponseOK(conn.RemoteMultiaddr())
+}
+
+// throttleAllow reports whether a dial-back request from p should be
+// allowed under the configured global and per-peer throttles, resetting the
+// window when it has elapsed.
+func (as *autoNATService) throttleAllow(p peer.ID) bool {
+	as.mx.Lock()
+	defer as.mx.Unlock()
+
+	if time.Since(as.lastReset) >= as.config.throttleResetInterval {
+		as.reqs = make(map[peer.ID]int)
+		as.globalReqs = 0
+		as.lastReset = time.Now()
+	}
+
+	if as.globalReqs >= as.config.throttleGlobalMax {
+		return false
+	}
+	if as.reqs[p] >= as.config.throttlePeerMax {
+		return false
+	}
+
+	as.reqs[p]++
+	as.globalReqs++
+	return true
+}
+
+func newDialResponseOK(addr ma.Multiaddr) *pb.Message {
+	dr := new(pb.Message_DialResponse)
+	dr.Status = pb.Message_OK.Enum()
+	dr.Addr = addr.Bytes()
+
+	m := new(pb.Message)
+	m.Type = pb.Message_DIAL_RESPONSE.Enum()
+	m.DialResponse = dr
+	return m
+}
+
+func newDialResponseError(status pb.Message_ResponseStatus, text string) *pb.Message {
+	dr := new(pb.Message_DialResponse)
+	dr.Status = status.Enum()
+	dr.StatusText = &text
+
+	m := new(pb.Message)
+	m.Type = pb.Message_DIAL_RESPONSE.Enum()
+	m.DialResponse = dr
+	return m
+}
@@ -0,0 +1,129 @@
+package autonat
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	pb "github.com/libp2p/go-libp2p-autonat/pb"
+
+	peer "github.com/libp2p/go-libp2p-core/peer"
+	ma "github.com/multiformats/go-multiaddr"
+	mh "github.com/multiformats/go-multihash"
+)
+
+func newTestService(c serviceConfig) *autoNATService {
+	return newAutoNATService(context.Background(), nil, nil, c)
+}
+
+func testPeerID(t *testing.T, seed string) peer.ID {
+	t.Helper()
+	h, err := mh.Sum([]byte(seed), mh.IDENTITY, -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return peer.ID(h)
+}
+
+func TestThrottleAllow(t *testing.T) {
+	c := serviceConfig{}
+	defaultServiceConfig(&c)
+	c.throttleGlobalMax = 2
+	c.throttlePeerMax = 1
+	c.throttleResetInterval = time.Hour
+	as := newTestService(c)
+
+	p1, p2 := peer.ID("peer1"), peer.ID("peer2")
+
+	if !as.throttleAllow(p1) {
+		t.Fatal("first request from p1 should be allowed")
+	}
+	if as.throttleAllow(p1) {
+		t.Fatal("second request from p1 should be refused by the per-peer throttle")
+	}
+	if !as.throttleAllow(p2) {
+		t.Fatal("first request from p2 should be allowed")
+	}
+	if as.throttleAllow(p2) {
+		t.Fatal("global throttle should already be exhausted")
+	}
+}
+
+func TestThrottleAllowResets(t *testing.T) {
+	c := serviceConfig{}
+	defaultServiceConfig(&c)
+	c.throttleGlobalMax = 1
+	c.throttlePeerMax = 1
+	c.throttleResetInterval = 10 * time.Millisecond
+	as := newTestService(c)
+
+	p := peer.ID("peer1")
+	if !as.throttleAllow(p) {
+		t.Fatal("first request should be allowed")
+	}
+	if as.throttleAllow(p) {
+		t.Fatal("second request before the window elapses should be refused")
+	}
+
+	time.Sleep(20 * time.Millisecond)
+
+	if !as.throttleAllow(p) {
+		t.Fatal("request after the throttle window elapses should be allowed again")
+	}
+}
+
+func TestHandleDialRefusals(t *testing.T) {
+	c := serviceConfig{}
+	defaultServiceConfig(&c)
+	as := newTestService(c)
+
+	p := testPeerID(t, "peer1")
+	addr, err := ma.NewMultiaddr("/ip4/1.2.3.4/tcp/1234")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("missing peer info", func(t *testing.T) {
+		res := as.handleDial(p, addr, nil)
+		if res.GetDialResponse().GetStatus() != pb.Message_E_BAD_REQUEST {
+			t.Fatalf("expected E_BAD_REQUEST, got %s", res.GetDialResponse().GetStatus())
+		}
+	})
+
+	t.Run("peer id mismatch", func(t *testing.T) {
+		mpi := &pb.Message_PeerInfo{Id: []byte(testPeerID(t, "someone-else"))}
+		res := as.handleDial(p, addr, mpi)
+		if res.GetDialResponse().GetStatus() != pb.Message_E_BAD_REQUEST {
+			t.Fatalf("expected E_BAD_REQUEST, got %s", res.GetDialResponse().GetStatus())
+		}
+	})
+
+	t.Run("no dialable addresses", func(t *testing.T) {
+		res := as.handleDial(p, nil, &pb.Message_PeerInfo{})
+		if res.GetDialResponse().GetStatus() != pb.Message_E_DIAL_REFUSED {
+			t.Fatalf("expected E_DIAL_REFUSED, got %s", res.GetDialResponse().GetStatus())
+		}
+	})
+
+	t.Run("too many dials", func(t *testing.T) {
+		as := newTestService(c)
+		as.config.throttlePeerMax = 0
+
+		res := as.handleDial(p, addr, &pb.Message_PeerInfo{})
+		if res.GetDialResponse().GetStatus() != pb.Message_E_DIAL_REFUSED {
+			t.Fatalf("expected E_DIAL_REFUSED from the throttle, got %s", res.GetDialResponse().GetStatus())
+		}
+	})
+
+	t.Run("too many concurrent dials", func(t *testing.T) {
+		cc := c
+		cc.maxConcurrentDials = 1
+		as := newTestService(cc)
+		as.dialSem <- struct{}{} // occupy the only slot
+
+		res := as.handleDial(p, addr, &pb.Message_PeerInfo{})
+		if res.GetDialResponse().GetStatus() != pb.Message_E_DIAL_REFUSED {
+			t.Fatalf("expected E_DIAL_REFUSED from the concurrency limit, got %s", res.GetDialResponse().GetStatus())
+		}
+	})
+}